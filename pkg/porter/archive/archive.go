@@ -0,0 +1,315 @@
+// Package archive writes and reads CNAB bundles as OCI Image Layout tarballs, so that a bundle
+// can be hand-carried into an environment that has no connectivity to the original registry.
+package archive
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/containerd/containerd/content"
+	ctrremotes "github.com/containerd/containerd/remotes"
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/cnab-to-oci/remotes"
+	"github.com/docker/distribution/reference"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+const layoutVersion = "1.0.0"
+
+// ExportOptions control how a bundle is exported to an OCI Image Layout archive.
+type ExportOptions struct {
+	// Bundle is the CNAB bundle to archive. Its image references are expected to already be
+	// fixed up to digests, e.g. via remotes.FixupBundle.
+	Bundle *bundle.Bundle
+	// Ref is the bundle tag that the archive's index.json records as the annotated entry.
+	Ref reference.Named
+	// Destination is the path to the tarball that will be written.
+	Destination string
+}
+
+// Export streams the bundle.json and every referenced image (invocation image plus each entry in
+// Bundle.Images) into an OCI Image Layout tarball at opts.Destination, using resolverConfig to
+// fetch blobs from the registry the bundle currently references.
+func Export(ctx context.Context, resolverConfig remotes.ResolverConfig, opts ExportOptions) error {
+	f, err := os.Create(opts.Destination)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create archive %s", opts.Destination)
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	defer tw.Close()
+
+	if err := writeLayoutMarker(tw); err != nil {
+		return err
+	}
+
+	var manifests []ocispec.Descriptor
+	for _, imgRefString := range allImageReferences(opts.Bundle) {
+		imgRef, err := reference.ParseNormalizedNamed(imgRefString)
+		if err != nil {
+			return errors.Wrapf(err, "invalid image reference %s in bundle", imgRefString)
+		}
+
+		desc, fetcher, err := resolverConfig.Resolver.Resolve(ctx, imgRef.String())
+		if err != nil {
+			return errors.Wrapf(err, "unable to resolve %s", imgRef)
+		}
+		if err := copyManifestAndBlobs(ctx, tw, fetcher, desc); err != nil {
+			return errors.Wrapf(err, "unable to archive %s", imgRef)
+		}
+		desc.Annotations = map[string]string{ocispec.AnnotationRefName: imgRef.String()}
+		manifests = append(manifests, desc)
+	}
+
+	bundleJSON, err := json.Marshal(opts.Bundle)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal bundle.json")
+	}
+	if err := writeTarEntry(tw, "bundle.json", bundleJSON); err != nil {
+		return err
+	}
+
+	index := ocispec.Index{
+		Versioned: ociVersioned(),
+		Manifests: manifests,
+		Annotations: map[string]string{
+			"io.cnab.bundletag": opts.Ref.String(),
+		},
+	}
+	indexJSON, err := json.Marshal(index)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal index.json")
+	}
+	return writeTarEntry(tw, "index.json", indexJSON)
+}
+
+// LoadOptions control how a bundle is read back out of an OCI Image Layout archive and pushed to
+// a destination registry.
+type LoadOptions struct {
+	// Source is the path to the archive written by Export.
+	Source string
+	// Destination is the bundle tag to rewrite image references to and push the bundle under.
+	Destination reference.Named
+}
+
+// Load reads the bundle.json out of the archive at opts.Source, pushes each referenced image's
+// manifest and blobs (read entirely from the archive) to a repository under opts.Destination's
+// registry, rewrites the bundle's image references to the pushed digests, and pushes the bundle
+// manifest there too.
+func Load(ctx context.Context, resolverConfig remotes.ResolverConfig, opts LoadOptions) (*bundle.Bundle, error) {
+	entries, err := readTar(opts.Source)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read archive %s", opts.Source)
+	}
+
+	bundleJSON, ok := entries["bundle.json"]
+	if !ok {
+		return nil, errors.Errorf("%s does not contain a bundle.json, is it a porter archive?", opts.Source)
+	}
+	bun, err := bundle.Unmarshal(bundleJSON)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse bundle.json from archive")
+	}
+
+	indexJSON, ok := entries["index.json"]
+	if !ok {
+		return nil, errors.Errorf("%s does not contain an index.json, is it a porter archive?", opts.Source)
+	}
+	var index ocispec.Index
+	if err := json.Unmarshal(indexJSON, &index); err != nil {
+		return nil, errors.Wrap(err, "unable to parse index.json from archive")
+	}
+
+	for _, manifestDesc := range index.Manifests {
+		origRef := manifestDesc.Annotations[ocispec.AnnotationRefName]
+		if origRef == "" {
+			continue
+		}
+		rewritten, err := pushImageFromArchive(ctx, resolverConfig, opts.Destination, entries, manifestDesc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to push %s from archive", origRef)
+		}
+		rewriteBundleImageReference(bun, origRef, rewritten)
+	}
+
+	if _, err := remotes.Push(ctx, bun, opts.Destination, resolverConfig.Resolver, true); err != nil {
+		return nil, errors.Wrap(err, "unable to push bundle read from archive")
+	}
+	return bun, nil
+}
+
+func allImageReferences(bun *bundle.Bundle) []string {
+	refs := []string{bun.InvocationImages[0].Image}
+	for _, img := range bun.Images {
+		refs = append(refs, img.Image)
+	}
+	return refs
+}
+
+func copyManifestAndBlobs(ctx context.Context, tw *tar.Writer, fetcher ctrremotes.Fetcher, desc ocispec.Descriptor) error {
+	manifestRC, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer manifestRC.Close()
+
+	manifestBytes, err := io.ReadAll(manifestRC)
+	if err != nil {
+		return err
+	}
+	if err := writeBlob(tw, desc.Digest, manifestBytes); err != nil {
+		return err
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return err
+	}
+	layers := append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...)
+	for _, layer := range layers {
+		rc, err := fetcher.Fetch(ctx, layer)
+		if err != nil {
+			return err
+		}
+		layerBytes, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if err := writeBlob(tw, layer.Digest, layerBytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBlob(tw *tar.Writer, dgst digest.Digest, data []byte) error {
+	return writeTarEntry(tw, path.Join("blobs", dgst.Algorithm().String(), dgst.Encoded()), data)
+}
+
+func writeLayoutMarker(tw *tar.Writer) error {
+	layout := ocispec.ImageLayout{Version: layoutVersion}
+	data, err := json.Marshal(layout)
+	if err != nil {
+		return err
+	}
+	return writeTarEntry(tw, "oci-layout", data)
+}
+
+func writeTarEntry(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func readTar(src string) (map[string][]byte, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := map[string][]byte{}
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		entries[path.Base(hdr.Name)] = data
+	}
+	return entries, nil
+}
+
+// pushImageFromArchive pushes manifestDesc and the config/layer blobs it references (all read
+// from entries, keyed by hex-encoded digest as readTar stores them) to a repository under dest's
+// registry named after origRef's path, and returns "<repo>@<manifest digest>".
+func pushImageFromArchive(ctx context.Context, resolverConfig remotes.ResolverConfig, dest reference.Named, entries map[string][]byte, manifestDesc ocispec.Descriptor) (string, error) {
+	origRef, err := reference.ParseNormalizedNamed(manifestDesc.Annotations[ocispec.AnnotationRefName])
+	if err != nil {
+		return "", err
+	}
+	destRepo := path.Join(reference.Domain(dest), reference.Path(origRef))
+	pusher, err := resolverConfig.Resolver.Pusher(ctx, destRepo)
+	if err != nil {
+		return "", err
+	}
+
+	manifestBytes, ok := entries[manifestDesc.Digest.Encoded()]
+	if !ok {
+		return "", errors.Errorf("archive is missing manifest blob %s", manifestDesc.Digest)
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return "", err
+	}
+	for _, layer := range append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...) {
+		layerBytes, ok := entries[layer.Digest.Encoded()]
+		if !ok {
+			return "", errors.Errorf("archive is missing blob %s", layer.Digest)
+		}
+		if err := pushBlob(ctx, pusher, layer.MediaType, layer.Digest, layerBytes); err != nil {
+			return "", errors.Wrapf(err, "unable to push blob %s", layer.Digest)
+		}
+	}
+	if err := pushBlob(ctx, pusher, manifestDesc.MediaType, manifestDesc.Digest, manifestBytes); err != nil {
+		return "", errors.Wrapf(err, "unable to push manifest %s", manifestDesc.Digest)
+	}
+
+	return fmt.Sprintf("%s@%s", destRepo, manifestDesc.Digest), nil
+}
+
+// rewriteBundleImageReference replaces whichever of bun's image references (the invocation image
+// or one of Images) still points at origRef with rewritten.
+func rewriteBundleImageReference(bun *bundle.Bundle, origRef, rewritten string) {
+	for i, img := range bun.InvocationImages {
+		if img.Image == origRef {
+			bun.InvocationImages[i].Image = rewritten
+		}
+	}
+	for name, img := range bun.Images {
+		if img.Image == origRef {
+			img.Image = rewritten
+			bun.Images[name] = img
+		}
+	}
+}
+
+func pushBlob(ctx context.Context, pusher ctrremotes.Pusher, mediaType string, dgst digest.Digest, blob []byte) error {
+	desc := ocispec.Descriptor{MediaType: mediaType, Digest: dgst, Size: int64(len(blob))}
+	writer, err := pusher.Push(ctx, desc)
+	if err != nil {
+		if errors.Cause(err) == ctrremotes.ErrResolverClosed || err == content.ErrExists {
+			return nil
+		}
+		return err
+	}
+	defer writer.Close()
+	if _, err := writer.Write(blob); err != nil {
+		return err
+	}
+	return writer.Commit(ctx, desc.Size, dgst)
+}
+
+func ociVersioned() ocispec.Versioned {
+	return ocispec.Versioned{SchemaVersion: 2}
+}
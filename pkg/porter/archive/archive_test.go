@@ -0,0 +1,42 @@
+package archive
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllImageReferences(t *testing.T) {
+	bun := &bundle.Bundle{
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: "example.com/bundles/myapp-installer:v1"}},
+		},
+		Images: map[string]bundle.Image{
+			"web": {BaseImage: bundle.BaseImage{Image: "example.com/bundles/myapp-web:v1"}},
+		},
+	}
+
+	refs := allImageReferences(bun)
+
+	assert.Len(t, refs, 2)
+	assert.Contains(t, refs, "example.com/bundles/myapp-installer:v1")
+	assert.Contains(t, refs, "example.com/bundles/myapp-web:v1")
+}
+
+func TestRewriteBundleImageReference(t *testing.T) {
+	bun := &bundle.Bundle{
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: "example.com/bundles/myapp-installer:v1"}},
+		},
+		Images: map[string]bundle.Image{
+			"web": {BaseImage: bundle.BaseImage{Image: "example.com/bundles/myapp-web:v1"}},
+		},
+	}
+
+	rewriteBundleImageReference(bun, "example.com/bundles/myapp-installer:v1", "dest.example.com/bundles/myapp-installer@sha256:abc")
+	rewriteBundleImageReference(bun, "example.com/bundles/myapp-web:v1", "dest.example.com/bundles/myapp-web@sha256:def")
+
+	assert.Equal(t, "dest.example.com/bundles/myapp-installer@sha256:abc", bun.InvocationImages[0].Image)
+	assert.Equal(t, "dest.example.com/bundles/myapp-web@sha256:def", bun.Images["web"].Image)
+}
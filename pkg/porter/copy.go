@@ -0,0 +1,257 @@
+package porter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/containerd/containerd/content"
+	ctrremotes "github.com/containerd/containerd/remotes"
+	"github.com/deislabs/cnab-go/bundle"
+	portercontext "github.com/deislabs/porter/pkg/context"
+	dockerconfig "github.com/docker/cli/cli/config"
+	"github.com/docker/cnab-to-oci/remotes"
+	"github.com/docker/distribution/reference"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// CopyOptions are options that may be specified when copying a bundle between registries.
+type CopyOptions struct {
+	// Source is the bundle tag to copy from.
+	Source string
+	// Destination is the bundle tag to copy to.
+	Destination string
+	// InsecureRegistries is the set of registry hosts (Source's and/or Destination's) to allow
+	// over plain HTTP or with self-signed certs.
+	InsecureRegistries []string
+	// SourceCreds is the path to a Docker config.json used to authenticate against Source.
+	// Defaults to the user's default Docker config file.
+	SourceCreds string
+	// DestinationCreds is the path to a Docker config.json used to authenticate against
+	// Destination. Defaults to the user's default Docker config file.
+	DestinationCreds string
+}
+
+// Validate performs validation on the copy options
+func (o *CopyOptions) Validate(cxt *portercontext.Context) error {
+	if o.Source == "" {
+		return errors.New("--source is required")
+	}
+	if o.Destination == "" {
+		return errors.New("--destination is required")
+	}
+	return nil
+}
+
+// Copy pulls the bundle manifest tagged opts.Source, pushes every image it references (the
+// invocation image plus each entry in bundle.json's images map) to opts.Destination, rewrites the
+// bundle's image references, and pushes the bundle manifest there too. This lets operators
+// promote bundles between registries, e.g. dev -> stage -> prod, without the original source
+// tree, the way `skopeo copy` does for plain images.
+func (p *Porter) Copy(opts CopyOptions) error {
+	srcRef, err := parseOCIReference(opts.Source)
+	if err != nil {
+		return errors.Wrap(err, "invalid --source reference")
+	}
+	destRef, err := parseOCIReference(opts.Destination)
+	if err != nil {
+		return errors.Wrap(err, "invalid --destination reference")
+	}
+
+	srcResolver, err := p.createResolverWithCreds(opts.InsecureRegistries, opts.SourceCreds)
+	if err != nil {
+		return errors.Wrap(err, "unable to load --src-creds")
+	}
+	destResolver, err := p.createResolverWithCreds(opts.InsecureRegistries, opts.DestinationCreds)
+	if err != nil {
+		return errors.Wrap(err, "unable to load --dest-creds")
+	}
+
+	ctx := context.Background()
+	fmt.Fprintf(p.Out, "Pulling bundle %s...\n", srcRef)
+	bun, err := pullBundle(ctx, srcResolver, srcRef)
+	if err != nil {
+		return errors.Wrapf(err, "unable to pull bundle %s", srcRef)
+	}
+
+	sameHost := reference.Domain(srcRef) == reference.Domain(destRef)
+	for _, image := range allBundleImages(bun) {
+		fmt.Fprintf(p.Out, "Copying image %s...\n", image)
+		if err := copyImage(ctx, srcResolver, destResolver, image, destRef, sameHost); err != nil {
+			return errors.Wrapf(err, "unable to copy image %s", image)
+		}
+	}
+
+	if err := remotes.FixupBundle(ctx, bun, destRef, destResolver, remotes.WithEventCallback(p.displayEvent)); err != nil {
+		return errors.Wrap(err, "unable to rewrite bundle image references")
+	}
+	d, err := remotes.Push(ctx, bun, destRef, destResolver.Resolver, true)
+	if err != nil {
+		return errors.Wrap(err, "unable to push bundle")
+	}
+
+	fmt.Fprintf(p.Out, "Bundle tag %s copied to %s, with digest %q\n", srcRef, destRef, d.Digest)
+	return nil
+}
+
+func (p *Porter) createResolverWithCreds(insecureRegistries []string, credsFile string) (remotes.ResolverConfig, error) {
+	if credsFile == "" {
+		return p.createResolver(insecureRegistries), nil
+	}
+
+	f, err := ioutil.ReadFile(credsFile)
+	if err != nil {
+		return remotes.ResolverConfig{}, errors.Wrapf(err, "unable to read %s", credsFile)
+	}
+	configFile, err := dockerconfig.LoadFromReader(bytes.NewReader(f))
+	if err != nil {
+		return remotes.ResolverConfig{}, errors.Wrapf(err, "unable to parse %s", credsFile)
+	}
+	return remotes.NewResolverConfigFromDockerConfigFile(configFile, insecureRegistries...), nil
+}
+
+func pullBundle(ctx context.Context, resolverConfig remotes.ResolverConfig, ref reference.Named) (*bundle.Bundle, error) {
+	desc, fetcher, err := resolverConfig.Resolver.Resolve(ctx, ref.String())
+	if err != nil {
+		return nil, err
+	}
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	bun, err := bundle.ParseReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return &bun, nil
+}
+
+func allBundleImages(bun *bundle.Bundle) []string {
+	images := []string{bun.InvocationImages[0].Image}
+	for _, img := range bun.Images {
+		images = append(images, img.Image)
+	}
+	return images
+}
+
+// copyImage streams image from src to dest's registry: the manifest plus its config and every
+// layer descriptor, so the destination ends up with every blob the manifest references instead
+// of just the manifest itself. When src and dest share a registry host, each blob is mounted
+// cross-repo instead of pulled and re-pushed, provided dest's pusher supports it; otherwise (or
+// when the mount fails, e.g. the registry doesn't support it) it falls back to pull-then-push.
+func copyImage(ctx context.Context, src, dest remotes.ResolverConfig, image string, destRef reference.Named, sameHost bool) error {
+	srcImageRef, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return err
+	}
+
+	manifestDesc, fetcher, err := src.Resolver.Resolve(ctx, srcImageRef.String())
+	if err != nil {
+		return err
+	}
+
+	destImageRef := fmt.Sprintf("%s/%s", reference.Domain(destRef), reference.Path(srcImageRef))
+	pusher, err := dest.Resolver.Pusher(ctx, destImageRef)
+	if err != nil {
+		return err
+	}
+
+	manifestData, err := fetchBlobBytes(ctx, fetcher, manifestDesc)
+	if err != nil {
+		return err
+	}
+	blobs, err := manifestBlobs(manifestData)
+	if err != nil {
+		return errors.Wrapf(err, "unable to parse manifest for %s", srcImageRef)
+	}
+
+	srcRepo := reference.Path(srcImageRef)
+	for _, blobDesc := range blobs {
+		if sameHost {
+			mounted, err := mountBlob(ctx, pusher, blobDesc, srcRepo)
+			if err != nil {
+				// The registry may not support cross-repo mount at all, or may reject this
+				// particular mount; either way, fall back to pull-then-push rather than
+				// aborting the whole copy.
+				mounted = false
+			}
+			if mounted {
+				continue
+			}
+		}
+		if err := copyBlob(ctx, fetcher, pusher, blobDesc); err != nil {
+			return errors.Wrapf(err, "unable to copy blob %s", blobDesc.Digest)
+		}
+	}
+
+	return copyBlobBytes(ctx, pusher, manifestDesc, manifestData)
+}
+
+// manifestBlobs extracts the config and layer descriptors referenced by a Docker v2 or OCI image
+// manifest; both share the same config/layers JSON shape.
+func manifestBlobs(manifestData []byte) ([]ocispec.Descriptor, error) {
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, err
+	}
+	return append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...), nil
+}
+
+// blobMounter is the optional interface of a pusher that can satisfy a cross-repo blob mount
+// without a pull-then-push round trip. mountBlob reports not mounted for a pusher that does not
+// implement it.
+type blobMounter interface {
+	Mount(ctx context.Context, desc ocispec.Descriptor, fromRepository string) error
+}
+
+func mountBlob(ctx context.Context, pusher ctrremotes.Pusher, desc ocispec.Descriptor, fromRepository string) (bool, error) {
+	mounter, ok := pusher.(blobMounter)
+	if !ok {
+		return false, nil
+	}
+	if err := mounter.Mount(ctx, desc, fromRepository); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func fetchBlobBytes(ctx context.Context, fetcher ctrremotes.Fetcher, desc ocispec.Descriptor) ([]byte, error) {
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+func copyBlob(ctx context.Context, fetcher ctrremotes.Fetcher, pusher ctrremotes.Pusher, desc ocispec.Descriptor) error {
+	data, err := fetchBlobBytes(ctx, fetcher, desc)
+	if err != nil {
+		return err
+	}
+	return copyBlobBytes(ctx, pusher, desc, data)
+}
+
+func copyBlobBytes(ctx context.Context, pusher ctrremotes.Pusher, desc ocispec.Descriptor, data []byte) error {
+	writer, err := pusher.Push(ctx, desc)
+	if err != nil {
+		if err == content.ErrExists {
+			return nil
+		}
+		return err
+	}
+	defer writer.Close()
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	return writer.Commit(ctx, desc.Size, desc.Digest)
+}
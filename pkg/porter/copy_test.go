@@ -0,0 +1,87 @@
+package porter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/containerd/containerd/content"
+	"github.com/deislabs/cnab-go/bundle"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMounter is a blobMounter whose Mount outcome is controlled by the test; Push is never
+// expected to be called by mountBlob itself.
+type fakeMounter struct {
+	mountErr error
+}
+
+func (f *fakeMounter) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	return nil, errors.New("push should not be called by mountBlob")
+}
+
+func (f *fakeMounter) Mount(ctx context.Context, desc ocispec.Descriptor, fromRepository string) error {
+	return f.mountErr
+}
+
+func TestMountBlob_Success(t *testing.T) {
+	mounted, err := mountBlob(context.Background(), &fakeMounter{}, ocispec.Descriptor{}, "library/myapp")
+
+	assert.NoError(t, err)
+	assert.True(t, mounted)
+}
+
+func TestMountBlob_FailureReportsNotMounted(t *testing.T) {
+	// copyImage relies on this (mounted=false, err!=nil) combination to know it must fall back
+	// to pull-then-push instead of treating the blob as already copied.
+	mounted, err := mountBlob(context.Background(), &fakeMounter{mountErr: errors.New("mount not supported")}, ocispec.Descriptor{}, "library/myapp")
+
+	assert.Error(t, err)
+	assert.False(t, mounted)
+}
+
+func TestAllBundleImages(t *testing.T) {
+	bun := &bundle.Bundle{
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: "example.com/bundles/myapp-installer:v1"}},
+		},
+		Images: map[string]bundle.Image{
+			"web": {BaseImage: bundle.BaseImage{Image: "example.com/bundles/myapp-web:v1"}},
+			"db":  {BaseImage: bundle.BaseImage{Image: "example.com/bundles/myapp-db:v1"}},
+		},
+	}
+
+	images := allBundleImages(bun)
+
+	assert.Len(t, images, 3)
+	assert.Contains(t, images, "example.com/bundles/myapp-installer:v1")
+	assert.Contains(t, images, "example.com/bundles/myapp-web:v1")
+	assert.Contains(t, images, "example.com/bundles/myapp-db:v1")
+}
+
+func TestManifestBlobs(t *testing.T) {
+	manifest := ocispec.Manifest{
+		Config: ocispec.Descriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: "sha256:config", Size: 10},
+		Layers: []ocispec.Descriptor{
+			{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: "sha256:layer1", Size: 100},
+			{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: "sha256:layer2", Size: 200},
+		},
+	}
+	data, err := json.Marshal(manifest)
+	assert.NoError(t, err)
+
+	blobs, err := manifestBlobs(data)
+
+	assert.NoError(t, err)
+	assert.Len(t, blobs, 3)
+	assert.Equal(t, manifest.Config.Digest, blobs[0].Digest)
+	assert.Equal(t, manifest.Layers[0].Digest, blobs[1].Digest)
+	assert.Equal(t, manifest.Layers[1].Digest, blobs[2].Digest)
+}
+
+func TestManifestBlobs_InvalidJSON(t *testing.T) {
+	_, err := manifestBlobs([]byte("not json"))
+	assert.Error(t, err)
+}
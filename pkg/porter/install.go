@@ -0,0 +1,38 @@
+package porter
+
+import (
+	portercontext "github.com/deislabs/porter/pkg/context"
+	"github.com/pkg/errors"
+)
+
+// InstallOptions are options that may be specified when installing a bundle.
+type InstallOptions struct {
+	bundleFileOptions
+	InsecureRegistry bool
+
+	// TrustPolicy is the path to a trust policy file listing the keys trusted to sign bundles.
+	// When set, Install refuses to proceed unless the bundle's signature verifies against it.
+	TrustPolicy string
+}
+
+// Validate performs validation on the install options
+func (o *InstallOptions) Validate(cxt *portercontext.Context) error {
+	return o.bundleFileOptions.Validate(cxt)
+}
+
+// Install verifies the bundle tagged in porter.yaml against opts.TrustPolicy, when configured,
+// and refuses to proceed if it does not verify. Otherwise it runs the bundle's install action.
+func (p *Porter) Install(opts InstallOptions) error {
+	if opts.TrustPolicy != "" {
+		verifyOpts := VerifyOptions{
+			bundleFileOptions: opts.bundleFileOptions,
+			InsecureRegistry:  opts.InsecureRegistry,
+			TrustPolicy:       opts.TrustPolicy,
+		}
+		if err := p.Verify(verifyOpts); err != nil {
+			return errors.Wrap(err, "unable to install an unverified bundle")
+		}
+	}
+
+	return p.executeBundleAction("install", opts.bundleFileOptions)
+}
@@ -0,0 +1,116 @@
+// Package manifestlist builds and pushes an OCI image index (or Docker manifest list) that
+// references one invocation image per platform, so a single CNAB bundle.json entry can resolve
+// to a multi-architecture invocation image.
+package manifestlist
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/containerd/containerd/content"
+	"github.com/docker/cnab-to-oci/remotes"
+	"github.com/docker/distribution/reference"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// Platform identifies one entry of a manifest list, e.g. "linux/arm64/v8".
+type Platform struct {
+	OS           string
+	Architecture string
+	Variant      string
+}
+
+// PlatformImage is a single platform's pushed invocation image, ready to be referenced from a
+// manifest list.
+type PlatformImage struct {
+	Platform Platform
+	Digest   digest.Digest
+	Size     int64
+	// MediaType is the media type of the platform's image manifest, e.g.
+	// application/vnd.docker.distribution.manifest.v2+json.
+	MediaType string
+}
+
+// Push assembles an application/vnd.oci.image.index.v1+json document referencing each of images
+// and pushes it to ref, returning the index's own descriptor. The caller rewrites the CNAB
+// invocation image reference to ref@<index digest> so the bundle points at the multi-arch entry.
+func Push(ctx context.Context, resolverConfig remotes.ResolverConfig, ref reference.Named, images []PlatformImage) (ocispec.Descriptor, error) {
+	if len(images) == 0 {
+		return ocispec.Descriptor{}, errors.New("no platform images given to assemble into a manifest list")
+	}
+
+	index := ocispec.Index{
+		Versioned: ocispec.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+	}
+	for _, img := range images {
+		index.Manifests = append(index.Manifests, ocispec.Descriptor{
+			MediaType: img.MediaType,
+			Digest:    img.Digest,
+			Size:      img.Size,
+			Platform: &ocispec.Platform{
+				OS:           img.Platform.OS,
+				Architecture: img.Platform.Architecture,
+				Variant:      img.Platform.Variant,
+			},
+		})
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return ocispec.Descriptor{}, errors.Wrap(err, "unable to marshal manifest list")
+	}
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+	}
+
+	pusher, err := resolverConfig.Resolver.Pusher(ctx, ref.String())
+	if err != nil {
+		return ocispec.Descriptor{}, errors.Wrapf(err, "unable to create pusher for %s", ref)
+	}
+	writer, err := pusher.Push(ctx, desc)
+	if err != nil {
+		if err == content.ErrExists {
+			return desc, nil
+		}
+		return ocispec.Descriptor{}, err
+	}
+	defer writer.Close()
+	if _, err := writer.Write(data); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := writer.Commit(ctx, desc.Size, desc.Digest); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return desc, nil
+}
+
+// ParsePlatform parses a "os/arch" or "os/arch/variant" string, the format porter.yaml's
+// `platforms` list uses.
+func ParsePlatform(s string) (Platform, error) {
+	parts := splitN(s, '/', 3)
+	switch len(parts) {
+	case 2:
+		return Platform{OS: parts[0], Architecture: parts[1]}, nil
+	case 3:
+		return Platform{OS: parts[0], Architecture: parts[1], Variant: parts[2]}, nil
+	default:
+		return Platform{}, errors.Errorf("invalid platform %q, expected OS/ARCH or OS/ARCH/VARIANT", s)
+	}
+}
+
+func splitN(s string, sep byte, n int) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s) && len(parts) < n-1; i++ {
+		if s[i] == sep {
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
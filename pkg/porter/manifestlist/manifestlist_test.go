@@ -0,0 +1,22 @@
+package manifestlist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlatform(t *testing.T) {
+	platform, err := ParsePlatform("linux/amd64")
+	assert.NoError(t, err)
+	assert.Equal(t, Platform{OS: "linux", Architecture: "amd64"}, platform)
+
+	platform, err = ParsePlatform("linux/arm/v7")
+	assert.NoError(t, err)
+	assert.Equal(t, Platform{OS: "linux", Architecture: "arm", Variant: "v7"}, platform)
+}
+
+func TestParsePlatform_Invalid(t *testing.T) {
+	_, err := ParsePlatform("linux")
+	assert.Error(t, err)
+}
@@ -0,0 +1,20 @@
+package porter
+
+import (
+	"io"
+
+	"github.com/deislabs/porter/pkg/config"
+	"github.com/deislabs/porter/pkg/porter/progress"
+)
+
+// Porter is the logic behind the porter client.
+type Porter struct {
+	*config.Config
+	Out io.Writer
+
+	// ProgressEvents, if non-nil, receives a copy of every progress.Event reported during
+	// long-running operations like Publish, so embedders get the events of
+	// --progress-format json without parsing stdout. Invocation image layer_progress events are
+	// only reported in json mode; in text and none modes the push progress is rendered as text.
+	ProgressEvents chan<- progress.Event
+}
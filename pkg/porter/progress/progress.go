@@ -0,0 +1,94 @@
+// Package progress defines a stable, machine-readable event schema for long-running porter
+// operations like publish, so CI systems and higher-level orchestrators can drive UIs off
+// `porter publish --progress-format json` without regex-scraping text output.
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// EventType identifies what stage of a publish a Event describes.
+type EventType string
+
+const (
+	// EventTypeLayerProgress reports incremental push/pull progress for a single invocation
+	// image layer, parsed out of the Docker jsonmessage stream.
+	EventTypeLayerProgress EventType = "layer_progress"
+	// EventTypeFixupStart reports that remotes.FixupBundle has begun copying a referenced image.
+	EventTypeFixupStart EventType = "fixup_start"
+	// EventTypeFixupEnd reports that remotes.FixupBundle has finished copying a referenced image.
+	EventTypeFixupEnd EventType = "fixup_end"
+	// EventTypeBundlePushed reports that the final bundle manifest was pushed.
+	EventTypeBundlePushed EventType = "bundle_pushed"
+)
+
+// Event is a single structured progress update. Only the fields relevant to Type are populated.
+type Event struct {
+	Type       EventType `json:"type"`
+	Ref        string    `json:"ref,omitempty"`
+	Layer      string    `json:"layer,omitempty"`
+	BytesDone  int64     `json:"bytes_done,omitempty"`
+	BytesTotal int64     `json:"bytes_total,omitempty"`
+	Digest     string    `json:"digest,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Format selects how Reporter renders events.
+type Format string
+
+const (
+	// FormatText renders nothing itself; callers keep using their existing human-oriented
+	// text output alongside the Reporter.
+	FormatText Format = "text"
+	// FormatJSON writes one NDJSON-encoded Event per line.
+	FormatJSON Format = "json"
+	// FormatNone discards events.
+	FormatNone Format = "none"
+)
+
+// Reporter fans a stream of Events out to an NDJSON writer (in FormatJSON) and/or a channel, so
+// both CLI consumers and library consumers embedding porter get the same feed.
+type Reporter struct {
+	Format Format
+	Out    io.Writer
+	// Events, if non-nil, receives a copy of every reported event. Send is best-effort: a full
+	// channel does not block or drop the CLI's own output.
+	Events chan<- Event
+
+	now func() time.Time
+}
+
+// NewReporter constructs a Reporter. out is where NDJSON is written when format is FormatJSON;
+// events may be nil.
+func NewReporter(format Format, out io.Writer, events chan<- Event) *Reporter {
+	return &Reporter{Format: format, Out: out, Events: events, now: time.Now}
+}
+
+// Report stamps ev.Timestamp (unless already set) and emits it per r.Format, then forwards it to
+// r.Events if configured.
+func (r *Reporter) Report(ev Event) {
+	if ev.Timestamp.IsZero() {
+		if r.now != nil {
+			ev.Timestamp = r.now()
+		} else {
+			ev.Timestamp = time.Now()
+		}
+	}
+
+	if r.Format == FormatJSON && r.Out != nil {
+		data, err := json.Marshal(ev)
+		if err == nil {
+			r.Out.Write(append(data, '\n'))
+		}
+	}
+
+	if r.Events != nil {
+		select {
+		case r.Events <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReporter_JSON(t *testing.T) {
+	var out bytes.Buffer
+	events := make(chan Event, 1)
+	r := NewReporter(FormatJSON, &out, events)
+	r.now = func() time.Time { return time.Unix(0, 0).UTC() }
+
+	r.Report(Event{Type: EventTypeBundlePushed, Ref: "example.com/bundles/myapp:v1", Digest: "sha256:abc"})
+
+	var got Event
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	assert.Equal(t, EventTypeBundlePushed, got.Type)
+	assert.Equal(t, "example.com/bundles/myapp:v1", got.Ref)
+
+	select {
+	case ev := <-events:
+		assert.Equal(t, "sha256:abc", ev.Digest)
+	default:
+		t.Fatal("expected event to be forwarded to Events channel")
+	}
+}
+
+func TestReporter_Text_NoJSONOutput(t *testing.T) {
+	var out bytes.Buffer
+	r := NewReporter(FormatText, &out, nil)
+
+	r.Report(Event{Type: EventTypeFixupStart})
+
+	assert.Empty(t, out.String())
+}
+
+func TestReporter_FullEventsChannelDoesNotBlock(t *testing.T) {
+	events := make(chan Event)
+	r := NewReporter(FormatNone, nil, events)
+
+	r.Report(Event{Type: EventTypeFixupEnd})
+}
@@ -3,20 +3,32 @@ package porter
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/deislabs/cnab-go/bundle"
 	portercontext "github.com/deislabs/porter/pkg/context"
+	"github.com/deislabs/porter/pkg/porter/archive"
+	"github.com/deislabs/porter/pkg/porter/manifestlist"
+	"github.com/deislabs/porter/pkg/porter/progress"
+	"github.com/deislabs/porter/pkg/porter/shortnames"
+	"github.com/deislabs/porter/pkg/porter/sign"
 	"github.com/docker/cli/cli/command"
 	dockerconfig "github.com/docker/cli/cli/config"
 	cliflags "github.com/docker/cli/cli/flags"
 	"github.com/docker/cnab-to-oci/remotes"
+	"github.com/docker/distribution/manifest/schema2"
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/docker/pkg/term"
 	"github.com/docker/docker/registry"
+	digest "github.com/opencontainers/go-digest"
 	"github.com/pkg/errors"
 )
 
@@ -25,10 +37,65 @@ import (
 type PublishOptions struct {
 	bundleFileOptions
 	InsecureRegistry bool
+
+	// Archive is the path to write an OCI Image Layout tarball to, instead of pushing the
+	// bundle to a live registry. Mutually exclusive with FromArchive.
+	Archive string
+
+	// FromArchive is the path to a tarball previously written via Archive. When set, Publish
+	// rewrites the bundle's image references to BundleTag's registry and pushes it there,
+	// instead of building and pushing from the local porter.yaml.
+	FromArchive string
+
+	// Sign, when true, produces a detached signature over the pushed bundle's digest and
+	// pushes it alongside the bundle.
+	Sign bool
+
+	// SignKeyFile is the path to a PEM-encoded private key used to sign the bundle.
+	SignKeyFile string
+
+	// SignKeyEnvVar is an environment variable holding a PEM-encoded private key used to sign
+	// the bundle. It is only used when SignKeyFile is empty.
+	SignKeyEnvVar string
+
+	// ShortNameMode controls how an un-prefixed bundle tag or invocation image name is resolved
+	// against ~/.config/porter/registries.conf: permissive, enforcing, or disabled.
+	// Defaults to permissive.
+	ShortNameMode string
+
+	// ProgressFormat controls how publish progress is reported: "text" (the default, human
+	// output on Porter.Out), "json" (one NDJSON progress.Event per line on stdout), or "none".
+	ProgressFormat string
 }
 
 // Validate performs validation on the publish options
 func (o *PublishOptions) Validate(cxt *portercontext.Context) error {
+	if o.Archive != "" && o.FromArchive != "" {
+		return errors.New("--archive and --from-archive cannot both be specified")
+	}
+
+	if o.Archive != "" && o.Sign {
+		return errors.New("--sign is not supported with --archive; sign the bundle when it is published from the archive instead")
+	}
+
+	if o.ShortNameMode == "" {
+		o.ShortNameMode = string(shortnames.ModePermissive)
+	}
+	switch shortnames.Mode(o.ShortNameMode) {
+	case shortnames.ModePermissive, shortnames.ModeEnforcing, shortnames.ModeDisabled:
+	default:
+		return errors.Errorf("invalid --short-name-mode %q, must be one of permissive, enforcing, disabled", o.ShortNameMode)
+	}
+
+	if o.ProgressFormat == "" {
+		o.ProgressFormat = string(progress.FormatText)
+	}
+	switch progress.Format(o.ProgressFormat) {
+	case progress.FormatText, progress.FormatJSON, progress.FormatNone:
+	default:
+		return errors.Errorf("invalid --progress-format %q, must be one of text, json, none", o.ProgressFormat)
+	}
+
 	err := o.bundleFileOptions.Validate(cxt)
 	if err != nil {
 		return err
@@ -44,6 +111,10 @@ func (o *PublishOptions) Validate(cxt *portercontext.Context) error {
 // Publish is a composite function that publishes an invocation image, rewrites the porter manifest
 // and then regenerates the bundle.json. Finally it [TODO] publishes the manifest to an OCI registry.
 func (p *Porter) Publish(opts PublishOptions) error {
+	if opts.FromArchive != "" {
+		return p.publishFromArchive(opts)
+	}
+
 	var err error
 	if opts.File != "" { // TODO: Extract validation from sharedOptions so that we aren't diverging logic from the other commands like we are here. Normally file is always populated by Validate.
 		err = p.Config.LoadManifestFrom(opts.File)
@@ -59,13 +130,17 @@ func (p *Porter) Publish(opts PublishOptions) error {
 		return err
 	}
 
+	reporter := progress.NewReporter(progress.Format(opts.ProgressFormat), p.Out, p.ProgressEvents)
+
 	ctx := context.Background()
 	cli, err := p.getDockerClient(ctx)
 	if err != nil {
 		return err
 	}
-	fmt.Fprintln(p.Out, "Pushing CNAB invocation image...")
-	digest, err := p.publishInvocationImage(ctx, cli)
+	if progress.Format(opts.ProgressFormat) == progress.FormatText {
+		fmt.Fprintln(p.Out, "Pushing CNAB invocation image...")
+	}
+	digest, err := p.publishInvocationImage(ctx, cli, opts.ShortNameMode, reporter, opts.InsecureRegistry)
 	if err != nil {
 		return errors.Wrap(err, "unable to push CNAB invocation image")
 	}
@@ -91,7 +166,7 @@ func (p *Porter) Publish(opts PublishOptions) error {
 		return errors.New("porter.yaml must specify a `tag` value for this bundle")
 	}
 
-	ref, err := parseOCIReference(p.Config.Manifest.BundleTag) //tag from manifest
+	ref, err := p.resolveShortName(ctx, p.Config.Manifest.BundleTag, opts.ShortNameMode, opts.InsecureRegistry) //tag from manifest
 	if err != nil {
 		return errors.Wrap(err, "invalid bundle tag reference. expected value is REGISTRY/bundle:tag")
 	}
@@ -103,15 +178,111 @@ func (p *Porter) Publish(opts PublishOptions) error {
 
 	resolverConfig := p.createResolver(insecureRegistries)
 
-	err = remotes.FixupBundle(context.Background(), &bun, ref, resolverConfig, remotes.WithEventCallback(p.displayEvent))
+	fixupOpts := []remotes.FixupOption{remotes.WithEventCallback(func(ev remotes.FixupEvent) {
+		p.displayEventWithProgress(ev, reporter)
+	})}
+	if len(p.Config.Manifest.Platforms) > 1 {
+		// The invocation image reference is already a multi-arch index digest; don't let
+		// FixupBundle flatten it down to a single platform's manifest.
+		fixupOpts = append(fixupOpts, remotes.WithAllowMultiArch())
+	}
+	err = remotes.FixupBundle(context.Background(), &bun, ref, resolverConfig, fixupOpts...)
 	if err != nil {
 		return err
 	}
+
+	if opts.Archive != "" {
+		fmt.Fprintf(p.Out, "\nWriting bundle to archive %s...\n", opts.Archive)
+		err = archive.Export(context.Background(), resolverConfig, archive.ExportOptions{
+			Bundle:      &bun,
+			Ref:         ref,
+			Destination: opts.Archive,
+		})
+		if err != nil {
+			return errors.Wrap(err, "unable to export bundle to archive")
+		}
+		fmt.Fprintf(p.Out, "Bundle archived to %s\n", opts.Archive)
+		return nil
+	}
+
 	d, err := remotes.Push(context.Background(), &bun, ref, resolverConfig.Resolver, true)
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(p.Out, "Bundle tag %s pushed successfully, with digest %q\n", ref, d.Digest)
+	if progress.Format(opts.ProgressFormat) == progress.FormatText {
+		fmt.Fprintf(p.Out, "Bundle tag %s pushed successfully, with digest %q\n", ref, d.Digest)
+	}
+	reporter.Report(progress.Event{Type: progress.EventTypeBundlePushed, Ref: ref.String(), Digest: d.Digest.String()})
+
+	if opts.Sign {
+		fmt.Fprintln(p.Out, "\nSigning bundle...")
+		if err := p.signBundle(ref.String(), d.Digest, opts); err != nil {
+			return errors.Wrap(err, "unable to sign bundle")
+		}
+		fmt.Fprintln(p.Out, "Bundle signature pushed successfully")
+	}
+	return nil
+}
+
+// signBundle produces a detached signature over dgst and pushes it to ref's repository as a
+// sibling artifact, per the cosign tag convention.
+func (p *Porter) signBundle(ref string, dgst digest.Digest, opts PublishOptions) error {
+	src := sign.KeySource{KeyFile: opts.SignKeyFile, KeyEnvVar: opts.SignKeyEnvVar}
+	sig, err := sign.Sign(context.Background(), ref, dgst, src)
+	if err != nil {
+		return err
+	}
+
+	insecureRegistries := []string{}
+	if opts.InsecureRegistry {
+		parsed, err := parseOCIReference(ref)
+		if err == nil {
+			insecureRegistries = append(insecureRegistries, reference.Domain(parsed))
+		}
+	}
+	resolverConfig := p.createResolver(insecureRegistries)
+	return sign.Push(context.Background(), resolverConfig, ref, dgst, sig)
+}
+
+// publishFromArchive re-uploads a bundle previously written to an OCI Image Layout tarball via
+// the --archive flag, rewriting its image references to point at the `tag` declared in
+// porter.yaml.
+func (p *Porter) publishFromArchive(opts PublishOptions) error {
+	var err error
+	if opts.File != "" {
+		err = p.Config.LoadManifestFrom(opts.File)
+	} else {
+		err = p.Config.LoadManifest()
+	}
+	if err != nil {
+		return err
+	}
+
+	if p.Config.Manifest.BundleTag == "" {
+		return errors.New("porter.yaml must specify a `tag` value for this bundle")
+	}
+
+	ref, err := parseOCIReference(p.Config.Manifest.BundleTag)
+	if err != nil {
+		return errors.Wrap(err, "invalid bundle tag reference. expected value is REGISTRY/bundle:tag")
+	}
+
+	insecureRegistries := []string{}
+	if opts.InsecureRegistry {
+		insecureRegistries = append(insecureRegistries, reference.Domain(ref))
+	}
+	resolverConfig := p.createResolver(insecureRegistries)
+
+	fmt.Fprintf(p.Out, "Loading bundle from archive %s...\n", opts.FromArchive)
+	bun, err := archive.Load(context.Background(), resolverConfig, archive.LoadOptions{
+		Source:      opts.FromArchive,
+		Destination: ref,
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to load bundle from archive")
+	}
+
+	fmt.Fprintf(p.Out, "Bundle tag %s pushed successfully from archive, bundle name %q\n", ref, bun.Name)
 	return nil
 }
 
@@ -120,15 +291,70 @@ func (p *Porter) createResolver(insecureRegistries []string) remotes.ResolverCon
 }
 
 func (p *Porter) displayEvent(ev remotes.FixupEvent) {
+	p.displayEventWithProgress(ev, progress.NewReporter(progress.FormatText, p.Out, p.ProgressEvents))
+}
+
+// displayEventWithProgress renders ev as text on p.Out when reporter is in text mode, and always
+// forwards it as a fixup_start/fixup_end progress.Event.
+func (p *Porter) displayEventWithProgress(ev remotes.FixupEvent, reporter *progress.Reporter) {
+	if reporter.Format == progress.FormatText {
+		switch ev.EventType {
+		case remotes.FixupEventTypeCopyImageStart:
+			fmt.Fprintf(p.Out, "Starting to copy image %s...\n", ev.SourceImage)
+		case remotes.FixupEventTypeCopyImageEnd:
+			if ev.Error != nil {
+				fmt.Fprintf(p.Out, "Failed to copy image %s: %s\n", ev.SourceImage, ev.Error)
+			} else {
+				fmt.Fprintf(p.Out, "Completed image %s copy\n", ev.SourceImage)
+			}
+		}
+	}
+
+	pev := progress.Event{Ref: ev.SourceImage}
 	switch ev.EventType {
 	case remotes.FixupEventTypeCopyImageStart:
-		fmt.Fprintf(p.Out, "Starting to copy image %s...\n", ev.SourceImage)
+		pev.Type = progress.EventTypeFixupStart
 	case remotes.FixupEventTypeCopyImageEnd:
+		pev.Type = progress.EventTypeFixupEnd
 		if ev.Error != nil {
-			fmt.Fprintf(p.Out, "Failed to copy image %s: %s\n", ev.SourceImage, ev.Error)
-		} else {
-			fmt.Fprintf(p.Out, "Completed image %s copy\n", ev.SourceImage)
+			pev.Error = ev.Error.Error()
+		}
+	default:
+		return
+	}
+	reporter.Report(pev)
+}
+
+// streamPushProgress consumes the Docker jsonmessage stream from an image push. In text mode it
+// renders the same human-oriented output DisplayJSONMessagesStream always has; in json mode it
+// instead decodes each message into a layer_progress progress.Event.
+func streamPushProgress(pushResponse io.Reader, out io.Writer, imageRef string, reporter *progress.Reporter) error {
+	if reporter.Format != progress.FormatJSON {
+		termFd, _ := term.GetFdInfo(out)
+		// Setting this to false here because Moby os.Exit(1) all over the place and this fails
+		// on WSL (only) when Term is true.
+		return jsonmessage.DisplayJSONMessagesStream(pushResponse, out, termFd, false, nil)
+	}
+
+	decoder := json.NewDecoder(pushResponse)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
 		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+
+		ev := progress.Event{Type: progress.EventTypeLayerProgress, Ref: imageRef, Layer: msg.ID}
+		if msg.Progress != nil {
+			ev.BytesDone = msg.Progress.Current
+			ev.BytesTotal = msg.Progress.Total
+		}
+		reporter.Report(ev)
 	}
 }
 
@@ -143,12 +369,25 @@ func (p *Porter) getDockerClient(ctx context.Context) (*command.DockerCli, error
 	return cli, nil
 }
 
-func (p *Porter) publishInvocationImage(ctx context.Context, cli *command.DockerCli) (string, error) {
+func (p *Porter) publishInvocationImage(ctx context.Context, cli *command.DockerCli, shortNameMode string, reporter *progress.Reporter, insecureRegistry bool) (string, error) {
+	if len(p.Config.Manifest.Platforms) > 1 {
+		return p.publishMultiArchInvocationImage(ctx, cli, shortNameMode, reporter, insecureRegistry)
+	}
 
-	ref, err := parseOCIReference(p.Config.Manifest.Image)
+	ref, err := p.resolveShortName(ctx, p.Config.Manifest.Image, shortNameMode, insecureRegistry)
 	if err != nil {
 		return "", err
 	}
+	// The locally built image is tagged as p.Config.Manifest.Image, which may be an
+	// un-prefixed short name; re-tag it to the resolved, fully-qualified ref so what gets
+	// pushed (and inspected afterward) is actually the registry the short name resolved to,
+	// not wherever the daemon's own default would otherwise land it.
+	if ref.String() != p.Config.Manifest.Image {
+		if err := cli.Client().ImageTag(ctx, p.Config.Manifest.Image, ref.String()); err != nil {
+			return "", errors.Wrapf(err, "unable to tag image as %s", ref)
+		}
+	}
+
 	// Resolve the Repository name from fqn to RepositoryInfo
 	repoInfo, err := registry.ParseRepositoryInfo(ref)
 	if err != nil {
@@ -164,30 +403,127 @@ func (p *Porter) publishInvocationImage(ctx context.Context, cli *command.Docker
 		RegistryAuth: encodedAuth,
 	}
 
-	pushResponse, err := cli.Client().ImagePush(ctx, p.Config.Manifest.Image, options)
+	pushResponse, err := cli.Client().ImagePush(ctx, ref.String(), options)
 	if err != nil {
 		return "", errors.Wrap(err, "docker push failed")
 	}
 	defer pushResponse.Close()
 
-	termFd, _ := term.GetFdInfo(p.Out)
-	// Setting this to false here because Moby os.Exit(1) all over the place and this fails on WSL (only)
-	// when Term is true.
-	isTerm := false
-	err = jsonmessage.DisplayJSONMessagesStream(pushResponse, p.Out, termFd, isTerm, nil)
+	err = streamPushProgress(pushResponse, p.Out, ref.String(), reporter)
 	if err != nil {
 		if strings.HasPrefix(err.Error(), "denied") {
 			return "", errors.Wrap(err, "docker push authentication failed")
 		}
 		return "", errors.Wrap(err, "failed to stream docker push stdout")
 	}
-	dist, err := cli.Client().DistributionInspect(ctx, p.Config.Manifest.Image, encodedAuth)
+	dist, err := cli.Client().DistributionInspect(ctx, ref.String(), encodedAuth)
 	if err != nil {
 		return "", errors.Wrap(err, "unable to inspect docker image")
 	}
 	return string(dist.Descriptor.Digest), nil
 }
 
+// publishMultiArchInvocationImage cross-builds and pushes one invocation image per platform
+// declared in porter.yaml's `platforms` list, tagged "<image>-<os>-<arch>[-<variant>]", assembles
+// them into an OCI image index, and returns the index's own digest so the CNAB bundle.json points
+// at the multi-arch entry. The Docker Engine API can't build for a platform other than the daemon's
+// own, so each platform is built and pushed with `docker buildx build --platform ... --push`
+// instead of the single-platform `docker build` + ImagePush path publishInvocationImage uses.
+func (p *Porter) publishMultiArchInvocationImage(ctx context.Context, cli *command.DockerCli, shortNameMode string, reporter *progress.Reporter, insecureRegistry bool) (string, error) {
+	ref, err := p.resolveShortName(ctx, p.Config.Manifest.Image, shortNameMode, insecureRegistry)
+	if err != nil {
+		return "", err
+	}
+
+	var platformImages []manifestlist.PlatformImage
+	for _, platformStr := range p.Config.Manifest.Platforms {
+		platform, err := manifestlist.ParsePlatform(platformStr)
+		if err != nil {
+			return "", err
+		}
+
+		platformImageRef := platformTaggedImage(ref.String(), platform)
+		if reporter.Format == progress.FormatText {
+			fmt.Fprintf(p.Out, "Building and pushing invocation image for %s...\n", platformStr)
+		}
+		if err := p.buildAndPushForPlatform(ctx, platformStr, platformImageRef); err != nil {
+			return "", errors.Wrapf(err, "unable to build and push invocation image for platform %s", platformStr)
+		}
+		dgst, size, err := p.inspectPushedImage(ctx, cli, platformImageRef)
+		if err != nil {
+			return "", errors.Wrapf(err, "unable to inspect pushed invocation image for platform %s", platformStr)
+		}
+
+		platformImages = append(platformImages, manifestlist.PlatformImage{
+			Platform:  platform,
+			Digest:    digest.Digest(dgst),
+			Size:      size,
+			MediaType: schema2.MediaTypeManifest,
+		})
+	}
+
+	insecureRegistries := []string{}
+	if insecureRegistry {
+		insecureRegistries = append(insecureRegistries, reference.Domain(ref))
+	}
+	resolverConfig := p.createResolver(insecureRegistries)
+	desc, err := manifestlist.Push(ctx, resolverConfig, ref, platformImages)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to push multi-arch manifest list")
+	}
+	return desc.Digest.String(), nil
+}
+
+// buildAndPushForPlatform cross-builds the invocation image's Dockerfile for platformStr (an
+// "os/arch[/variant]" string) and pushes the result as taggedRef, via the host's `docker buildx`
+// plugin. BuildKit, not the Engine API, is what can actually produce a non-native-arch image, so
+// this shells out rather than going through cli.Client().ImageBuild.
+func (p *Porter) buildAndPushForPlatform(ctx context.Context, platformStr, taggedRef string) error {
+	cmd := exec.CommandContext(ctx, "docker", "buildx", "build",
+		"--platform", platformStr,
+		"--tag", taggedRef,
+		"--push",
+		".")
+	cmd.Stdout = p.Out
+	cmd.Stderr = p.Out
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "docker buildx build --platform %s failed", platformStr)
+	}
+	return nil
+}
+
+// inspectPushedImage returns the digest and size of imageRef's manifest, already pushed by
+// buildAndPushForPlatform.
+func (p *Porter) inspectPushedImage(ctx context.Context, cli *command.DockerCli, imageRef string) (string, int64, error) {
+	ref, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return "", 0, err
+	}
+	repoInfo, err := registry.ParseRepositoryInfo(ref)
+	if err != nil {
+		return "", 0, err
+	}
+	authConfig := command.ResolveAuthConfig(ctx, cli, repoInfo.Index)
+	encodedAuth, err := command.EncodeAuthToBase64(authConfig)
+	if err != nil {
+		return "", 0, err
+	}
+
+	dist, err := cli.Client().DistributionInspect(ctx, imageRef, encodedAuth)
+	if err != nil {
+		return "", 0, errors.Wrap(err, "unable to inspect docker image")
+	}
+	return string(dist.Descriptor.Digest), dist.Descriptor.Size, nil
+}
+
+func platformTaggedImage(image string, platform manifestlist.Platform) string {
+	suffix := platform.OS + "-" + platform.Architecture
+	if platform.Variant != "" {
+		suffix += "-" + platform.Variant
+	}
+	return fmt.Sprintf("%s-%s", image, suffix)
+}
+
 func (p *Porter) rewriteImageWithDigest(InvocationImage string, digest string) (string, error) {
 	ref, err := reference.Parse(InvocationImage)
 	if err != nil {
@@ -202,4 +538,39 @@ func (p *Porter) rewriteImageWithDigest(InvocationImage string, digest string) (
 
 func parseOCIReference(ociRef string) (reference.Named, error) {
 	return reference.ParseNormalizedNamed(ociRef)
+}
+
+// resolveShortName parses ociRef, routing un-prefixed names through the shortnames resolver
+// configured by ~/.config/porter/registries.conf and mode instead of silently defaulting to
+// docker.io. Each candidate registry in unqualified-search-registries is probed by actually
+// resolving it, so an image that only exists in one of several configured registries is picked
+// correctly instead of treated as present everywhere.
+func (p *Porter) resolveShortName(ctx context.Context, ociRef string, mode string, insecureRegistry bool) (reference.Named, error) {
+	configPath, err := shortNamesConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := shortnames.NewResolver(configPath, shortnames.Mode(mode))
+	if err != nil {
+		return nil, err
+	}
+	resolver.Probe = func(candidate string) bool {
+		insecureRegistries := []string{}
+		if insecureRegistry {
+			if candidateRef, err := reference.ParseNormalizedNamed(candidate); err == nil {
+				insecureRegistries = append(insecureRegistries, reference.Domain(candidateRef))
+			}
+		}
+		_, _, err := p.createResolver(insecureRegistries).Resolver.Resolve(ctx, candidate)
+		return err == nil
+	}
+	return resolver.Resolve(ociRef)
+}
+
+func shortNamesConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "unable to determine home directory")
+	}
+	return filepath.Join(home, ".config", "porter", "registries.conf"), nil
 }
\ No newline at end of file
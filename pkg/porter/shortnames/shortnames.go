@@ -0,0 +1,190 @@
+// Package shortnames resolves un-prefixed image and bundle references (e.g. "mybundle:v1")
+// against a registries.conf file, modeled on containers/image's containers-registries.conf,
+// instead of silently defaulting them to docker.io.
+package shortnames
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// Mode controls how Resolve behaves when a short name is ambiguous and stdin is not a TTY.
+type Mode string
+
+const (
+	// ModePermissive picks the first candidate registry that resolves successfully.
+	ModePermissive Mode = "permissive"
+	// ModeEnforcing fails on an ambiguous name when stdin is not a TTY, unless registries.conf
+	// already has an alias for it.
+	ModeEnforcing Mode = "enforcing"
+	// ModeDisabled skips short-name resolution entirely; names are passed through unqualified
+	// to reference.ParseNormalizedNamed, which defaults to docker.io as before.
+	ModeDisabled Mode = "disabled"
+)
+
+// config is the on-disk schema of registries.conf.
+type config struct {
+	UnqualifiedSearchRegistries []string          `toml:"unqualified-search-registries"`
+	Aliases                     map[string]string `toml:"aliases"`
+}
+
+// Resolver resolves short names to fully-qualified references using a loaded registries.conf,
+// probing candidate registries and persisting newly-confirmed choices back to disk.
+type Resolver struct {
+	Mode Mode
+	// Path is the registries.conf file that aliases are loaded from and persisted to.
+	Path string
+	// Prompt asks the user to choose among candidates when more than one probes successfully.
+	// Defaults to promptOnTTY, overridable in tests.
+	Prompt func(shortName string, candidates []string) (string, error)
+	// Probe reports whether candidate (a fully-qualified reference) exists. NewResolver sets it
+	// to treat every candidate as existing; Porter's resolveShortName replaces it with one that
+	// resolves each candidate against its registry.
+	Probe func(candidate string) bool
+
+	cfg config
+}
+
+// NewResolver loads registries.conf at path (creating an empty one does not happen implicitly;
+// a missing file just means no aliases and the default search list).
+func NewResolver(path string, mode Mode) (*Resolver, error) {
+	r := &Resolver{Mode: mode, Path: path, Probe: func(string) bool { return true }}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			r.cfg = config{UnqualifiedSearchRegistries: []string{"docker.io"}}
+			return r, nil
+		}
+		return nil, errors.Wrapf(err, "unable to read %s", path)
+	}
+	if err := toml.Unmarshal(data, &r.cfg); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %s", path)
+	}
+	if len(r.cfg.UnqualifiedSearchRegistries) == 0 {
+		r.cfg.UnqualifiedSearchRegistries = []string{"docker.io"}
+	}
+	return r, nil
+}
+
+// Resolve turns a possibly-unqualified name into a fully-qualified reference.Named. If name is
+// already qualified (has a registry component), it is returned as-is via
+// reference.ParseNormalizedNamed.
+func (r *Resolver) Resolve(name string) (reference.Named, error) {
+	if r.Mode == ModeDisabled || isQualified(name) {
+		return reference.ParseNormalizedNamed(name)
+	}
+
+	if alias, ok := r.cfg.Aliases[name]; ok {
+		return reference.ParseNormalizedNamed(alias)
+	}
+
+	var candidates []string
+	for _, registry := range r.cfg.UnqualifiedSearchRegistries {
+		candidate := fmt.Sprintf("%s/%s", registry, name)
+		if r.Probe(candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, errors.Errorf("%s is not present in any of the configured unqualified-search-registries", name)
+	case 1:
+		return r.confirm(name, candidates[0])
+	default:
+		chosen, err := r.choose(name, candidates)
+		if err != nil {
+			return nil, err
+		}
+		return r.confirm(name, chosen)
+	}
+}
+
+func (r *Resolver) choose(shortName string, candidates []string) (string, error) {
+	if isTTY() {
+		prompt := r.Prompt
+		if prompt == nil {
+			prompt = promptOnTTY
+		}
+		return prompt(shortName, candidates)
+	}
+	if r.Mode == ModePermissive {
+		return candidates[0], nil
+	}
+	return "", errors.Errorf("%s is ambiguous across %v and stdin is not a terminal; rerun interactively or add an alias to %s", shortName, candidates, r.Path)
+}
+
+func (r *Resolver) confirm(shortName, chosen string) (reference.Named, error) {
+	named, err := reference.ParseNormalizedNamed(chosen)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.persistAlias(shortName, chosen); err != nil {
+		return nil, err
+	}
+	return named, nil
+}
+
+func (r *Resolver) persistAlias(shortName, resolved string) error {
+	if r.cfg.Aliases == nil {
+		r.cfg.Aliases = map[string]string{}
+	}
+	r.cfg.Aliases[shortName] = resolved
+
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0755); err != nil {
+		return errors.Wrapf(err, "unable to create %s", filepath.Dir(r.Path))
+	}
+	f, err := os.Create(r.Path)
+	if err != nil {
+		return errors.Wrapf(err, "unable to write %s", r.Path)
+	}
+	defer f.Close()
+	return toml.NewEncoder(f).Encode(r.cfg)
+}
+
+// isQualified reports whether name already carries an explicit registry host, using the same
+// heuristic as docker/distribution/reference: the portion before the first "/" counts as a host
+// only if it contains a "." or ":" or is exactly "localhost".
+func isQualified(name string) bool {
+	firstSlash := strings.Index(name, "/")
+	if firstSlash == -1 {
+		return false
+	}
+	host := name[:firstSlash]
+	return strings.ContainsAny(host, ".:") || host == "localhost"
+}
+
+func isTTY() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && (fi.Mode()&os.ModeCharDevice) != 0
+}
+
+func promptOnTTY(shortName string, candidates []string) (string, error) {
+	fmt.Printf("%s is ambiguous, please select a registry:\n", shortName)
+	for i, c := range candidates {
+		fmt.Printf("  %d) %s\n", i+1, c)
+	}
+	fmt.Print("Enter a number: ")
+
+	reader := bufio.NewReader(os.Stdin)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", errors.Wrap(err, "unable to read selection")
+	}
+	line = strings.TrimSpace(line)
+
+	var choice int
+	if _, err := fmt.Sscanf(line, "%d", &choice); err != nil || choice < 1 || choice > len(candidates) {
+		return "", errors.Errorf("invalid selection %q", line)
+	}
+	return candidates[choice-1], nil
+}
@@ -0,0 +1,57 @@
+package shortnames
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve_SingleCandidateProbeSucceeds(t *testing.T) {
+	r := &Resolver{
+		Mode: ModePermissive,
+		Path: filepath.Join(t.TempDir(), "registries.conf"),
+		cfg:  config{UnqualifiedSearchRegistries: []string{"registry-a.example.com", "registry-b.example.com"}},
+		Probe: func(candidate string) bool {
+			return candidate == "registry-b.example.com/mybundle:v1"
+		},
+	}
+
+	ref, err := r.Resolve("mybundle:v1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "registry-b.example.com/mybundle:v1", ref.String())
+}
+
+func TestResolve_NoCandidateProbesSucceed(t *testing.T) {
+	r := &Resolver{
+		Mode:  ModePermissive,
+		Path:  filepath.Join(t.TempDir(), "registries.conf"),
+		cfg:   config{UnqualifiedSearchRegistries: []string{"registry-a.example.com"}},
+		Probe: func(candidate string) bool { return false },
+	}
+
+	_, err := r.Resolve("mybundle:v1")
+
+	assert.Error(t, err)
+}
+
+func TestResolve_QualifiedNameSkipsProbe(t *testing.T) {
+	r := &Resolver{
+		Mode:  ModePermissive,
+		Path:  filepath.Join(t.TempDir(), "registries.conf"),
+		Probe: func(candidate string) bool { t.Fatal("Probe should not be called for a qualified name"); return false },
+	}
+
+	ref, err := r.Resolve("registry.example.com/mybundle:v1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.example.com/mybundle:v1", ref.String())
+}
+
+func TestIsQualified(t *testing.T) {
+	assert.False(t, isQualified("mybundle:v1"))
+	assert.True(t, isQualified("registry.example.com/mybundle:v1"))
+	assert.True(t, isQualified("localhost/mybundle:v1"))
+	assert.True(t, isQualified("localhost:5000/mybundle:v1"))
+}
@@ -0,0 +1,190 @@
+// Package sign produces and pushes detached signatures over a published bundle's manifest
+// digest, and verifies them against a trust policy before install/upgrade.
+package sign
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/containerd/containerd/content"
+	ctrremotes "github.com/containerd/containerd/remotes"
+	"github.com/docker/cnab-to-oci/remotes"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// KeySource describes where the signing key material comes from.
+type KeySource struct {
+	// KeyFile is the path to a local PEM-encoded private key.
+	KeyFile string
+	// KeyEnvVar is the name of an environment variable holding a PEM-encoded private key.
+	KeyEnvVar string
+	// KMS, when set, is used instead of KeyFile/KeyEnvVar to sign via a remote key management
+	// service (AWS KMS, GCP KMS, Azure Key Vault, HashiCorp Vault).
+	KMS KMSSigner
+}
+
+// KMSSigner is implemented by pluggable key management service clients so that private key
+// material never has to leave the KMS.
+type KMSSigner interface {
+	// Sign returns a signature over digest using the KMS-managed key identified by keyID.
+	Sign(ctx context.Context, keyID string, digest []byte) ([]byte, error)
+	// KeyID identifies the key that Sign will use, for inclusion in the signature payload.
+	KeyID() string
+}
+
+// simpleSigningPayload is the cosign/simple-signing style payload that gets signed, binding the
+// signature to a specific image reference and manifest digest.
+type simpleSigningPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// Signature is a detached signature over a bundle manifest digest, ready to be pushed as a
+// sibling artifact of the bundle.
+type Signature struct {
+	Payload   []byte `json:"payload"`
+	Signature []byte `json:"signature"`
+	KeyID     string `json:"keyId,omitempty"`
+}
+
+// Sign produces a detached Signature over ref@dgst using the key material described by src.
+func Sign(ctx context.Context, ref string, dgst digest.Digest, src KeySource) (*Signature, error) {
+	payload := simpleSigningPayload{}
+	payload.Critical.Identity.DockerReference = ref
+	payload.Critical.Image.DockerManifestDigest = dgst.String()
+	payload.Critical.Type = "cosign container image signature"
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal signature payload")
+	}
+
+	if src.KMS != nil {
+		h := sha256.Sum256(payloadBytes)
+		sig, err := src.KMS.Sign(ctx, src.KMS.KeyID(), h[:])
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to sign digest with KMS")
+		}
+		return &Signature{Payload: payloadBytes, Signature: sig, KeyID: src.KMS.KeyID()}, nil
+	}
+
+	key, err := loadPrivateKey(src)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signWithKey(key, payloadBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to sign digest")
+	}
+	return &Signature{Payload: payloadBytes, Signature: sig}, nil
+}
+
+// Push uploads sig to the same repository as ref, tagged per the cosign convention
+// sha256-<digest>.sig.
+func Push(ctx context.Context, resolverConfig remotes.ResolverConfig, ref string, dgst digest.Digest, sig *Signature) error {
+	sigTag := fmt.Sprintf("%s:sha256-%s.sig", repository(ref), dgst.Encoded())
+
+	data, err := json.Marshal(sig)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal signature")
+	}
+
+	pusher, err := resolverConfig.Resolver.Pusher(ctx, sigTag)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create pusher for %s", sigTag)
+	}
+	return pushBytes(ctx, pusher, data)
+}
+
+func repository(ref string) string {
+	if idx := strings.LastIndex(ref, "@"); idx != -1 {
+		return ref[:idx]
+	}
+	if idx := strings.LastIndex(ref, ":"); idx != -1 {
+		return ref[:idx]
+	}
+	return ref
+}
+
+func loadPrivateKey(src KeySource) (crypto.Signer, error) {
+	var pemBytes []byte
+	var err error
+	switch {
+	case src.KeyFile != "":
+		pemBytes, err = ioutil.ReadFile(src.KeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read signing key %s", src.KeyFile)
+		}
+	case src.KeyEnvVar != "":
+		pemBytes = []byte(os.Getenv(src.KeyEnvVar))
+		if len(pemBytes) == 0 {
+			return nil, errors.Errorf("environment variable %s is empty or not set", src.KeyEnvVar)
+		}
+	default:
+		return nil, errors.New("no signing key configured: specify --signer key file, env var, or KMS")
+	}
+
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("unable to decode PEM signing key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse signing key")
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("signing key does not support signing")
+	}
+	return signer, nil
+}
+
+func signWithKey(key crypto.Signer, payload []byte) ([]byte, error) {
+	h := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return ecdsa.SignASN1(rand.Reader, k, h[:])
+	default:
+		return key.Sign(rand.Reader, h[:], crypto.SHA256)
+	}
+}
+
+func pushBytes(ctx context.Context, pusher ctrremotes.Pusher, data []byte) error {
+	dgst := digest.FromBytes(data)
+	desc := ocispec.Descriptor{
+		MediaType: "application/vnd.dev.cosign.simplesigning.v1+json",
+		Digest:    dgst,
+		Size:      int64(len(data)),
+	}
+	writer, err := pusher.Push(ctx, desc)
+	if err != nil {
+		if err == content.ErrExists {
+			return nil
+		}
+		return err
+	}
+	defer writer.Close()
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	return writer.Commit(ctx, desc.Size, dgst)
+}
@@ -0,0 +1,86 @@
+package sign
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+)
+
+func writePrivateKey(t *testing.T, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	assert.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return path
+}
+
+func writePublicKey(t *testing.T, pub interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.pub.pem")
+	assert.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	return path
+}
+
+func TestSignAndVerify_ECDSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	keyFile := writePrivateKey(t, key)
+	pubFile := writePublicKey(t, &key.PublicKey)
+
+	dgst := digest.FromString("test content")
+	sig, err := Sign(context.Background(), "example.com/bundles/myapp:v1", dgst, KeySource{KeyFile: keyFile})
+	assert.NoError(t, err)
+
+	policy := &TrustPolicy{Keys: []TrustedKey{{KeyFile: pubFile}}}
+	fetchSignature := func(ctx context.Context) (*Signature, error) { return sig, nil }
+	assert.NoError(t, Verify(context.Background(), fetchSignature, "example.com/bundles/myapp:v1", dgst, policy))
+}
+
+func TestSignAndVerify_RSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	keyFile := writePrivateKey(t, key)
+	pubFile := writePublicKey(t, &key.PublicKey)
+
+	dgst := digest.FromString("test content")
+	sig, err := Sign(context.Background(), "example.com/bundles/myapp:v1", dgst, KeySource{KeyFile: keyFile})
+	assert.NoError(t, err)
+
+	policy := &TrustPolicy{Keys: []TrustedKey{{KeyFile: pubFile}}}
+	fetchSignature := func(ctx context.Context) (*Signature, error) { return sig, nil }
+	assert.NoError(t, Verify(context.Background(), fetchSignature, "example.com/bundles/myapp:v1", dgst, policy))
+}
+
+func TestVerify_WrongDigestFails(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	keyFile := writePrivateKey(t, key)
+	pubFile := writePublicKey(t, &key.PublicKey)
+
+	dgst := digest.FromString("test content")
+	sig, err := Sign(context.Background(), "example.com/bundles/myapp:v1", dgst, KeySource{KeyFile: keyFile})
+	assert.NoError(t, err)
+
+	policy := &TrustPolicy{Keys: []TrustedKey{{KeyFile: pubFile}}}
+	fetchSignature := func(ctx context.Context) (*Signature, error) { return sig, nil }
+	otherDigest := digest.FromString("different content")
+	assert.Error(t, Verify(context.Background(), fetchSignature, "example.com/bundles/myapp:v1", otherDigest, policy))
+}
+
+func TestMatchesRepository(t *testing.T) {
+	assert.True(t, matchesRepository("example.com/bundles/myapp:v1", "example.com/bundles/"))
+	assert.False(t, matchesRepository("example.com/other/myapp:v1", "example.com/bundles/"))
+}
@@ -0,0 +1,112 @@
+package sign
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// TrustPolicy lists the public keys that are trusted to sign bundles, optionally scoped to a
+// repository prefix.
+type TrustPolicy struct {
+	Keys []TrustedKey `yaml:"keys"`
+}
+
+// TrustedKey is a single entry in a TrustPolicy.
+type TrustedKey struct {
+	// Repository is a prefix match against the bundle reference, e.g. "example.com/bundles/".
+	// An empty value matches any repository.
+	Repository string `yaml:"repository"`
+	// KeyFile is the path to the PEM-encoded public key trusted for Repository.
+	KeyFile string `yaml:"keyFile"`
+}
+
+// LoadTrustPolicy reads a trust policy file from path.
+func LoadTrustPolicy(path string) (*TrustPolicy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read trust policy %s", path)
+	}
+	var policy TrustPolicy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse trust policy %s", path)
+	}
+	return &policy, nil
+}
+
+// Verify fetches the signature for ref@dgst and checks it against policy, returning an error if
+// no trusted key verifies it.
+func Verify(ctx context.Context, fetchSignature func(context.Context) (*Signature, error), ref string, dgst digest.Digest, policy *TrustPolicy) error {
+	sig, err := fetchSignature(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to fetch bundle signature")
+	}
+
+	var payload simpleSigningPayload
+	if err := json.Unmarshal(sig.Payload, &payload); err != nil {
+		return errors.Wrap(err, "unable to parse signature payload")
+	}
+	if payload.Critical.Image.DockerManifestDigest != dgst.String() {
+		return errors.Errorf("signature digest %s does not match bundle digest %s", payload.Critical.Image.DockerManifestDigest, dgst)
+	}
+
+	for _, trusted := range policy.Keys {
+		if trusted.Repository != "" && !matchesRepository(ref, trusted.Repository) {
+			continue
+		}
+		pub, err := loadPublicKey(trusted.KeyFile)
+		if err != nil {
+			return err
+		}
+		if verifyWithKey(pub, sig.Payload, sig.Signature) {
+			return nil
+		}
+	}
+	return errors.Errorf("no trusted key in the policy verified the signature for %s", ref)
+}
+
+func matchesRepository(ref, prefix string) bool {
+	return len(ref) >= len(prefix) && ref[:len(prefix)] == prefix
+}
+
+func loadPublicKey(path string) (interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read trusted key %s", path)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Errorf("unable to decode PEM public key %s", path)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse public key %s", path)
+	}
+	return pub, nil
+}
+
+// verifyWithKey checks sig against payload for every key type signWithKey can produce: ecdsa.
+// PrivateKey's ASN.1 signatures, and PKCS1v15 for every other crypto.Signer (signWithKey's
+// default case calls key.Sign with a plain crypto.SHA256 hash, which rsa.PrivateKey.Sign treats
+// as a PKCS1v15 request).
+func verifyWithKey(pub interface{}, payload, sig []byte) bool {
+	h := sha256.Sum256(payload)
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, h[:], sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig) == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,38 @@
+package porter
+
+import (
+	portercontext "github.com/deislabs/porter/pkg/context"
+	"github.com/pkg/errors"
+)
+
+// UpgradeOptions are options that may be specified when upgrading a bundle installation.
+type UpgradeOptions struct {
+	bundleFileOptions
+	InsecureRegistry bool
+
+	// TrustPolicy is the path to a trust policy file listing the keys trusted to sign bundles.
+	// When set, Upgrade refuses to proceed unless the bundle's signature verifies against it.
+	TrustPolicy string
+}
+
+// Validate performs validation on the upgrade options
+func (o *UpgradeOptions) Validate(cxt *portercontext.Context) error {
+	return o.bundleFileOptions.Validate(cxt)
+}
+
+// Upgrade verifies the bundle tagged in porter.yaml against opts.TrustPolicy, when configured,
+// and refuses to proceed if it does not verify. Otherwise it runs the bundle's upgrade action.
+func (p *Porter) Upgrade(opts UpgradeOptions) error {
+	if opts.TrustPolicy != "" {
+		verifyOpts := VerifyOptions{
+			bundleFileOptions: opts.bundleFileOptions,
+			InsecureRegistry:  opts.InsecureRegistry,
+			TrustPolicy:       opts.TrustPolicy,
+		}
+		if err := p.Verify(verifyOpts); err != nil {
+			return errors.Wrap(err, "unable to upgrade to an unverified bundle")
+		}
+	}
+
+	return p.executeBundleAction("upgrade", opts.bundleFileOptions)
+}
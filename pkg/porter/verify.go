@@ -0,0 +1,122 @@
+package porter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	portercontext "github.com/deislabs/porter/pkg/context"
+	"github.com/deislabs/porter/pkg/porter/sign"
+	"github.com/docker/cnab-to-oci/remotes"
+	"github.com/docker/distribution/reference"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// VerifyOptions are options that may be specified when verifying a bundle's signature.
+type VerifyOptions struct {
+	bundleFileOptions
+	InsecureRegistry bool
+
+	// TrustPolicy is the path to a trust policy file listing the keys trusted to sign bundles.
+	TrustPolicy string
+}
+
+// Validate performs validation on the verify options
+func (o *VerifyOptions) Validate(cxt *portercontext.Context) error {
+	if o.TrustPolicy == "" {
+		return errors.New("--trust-policy is required")
+	}
+	return o.bundleFileOptions.Validate(cxt)
+}
+
+// Verify fetches the signature for the bundle tagged in porter.yaml, checks it against
+// opts.TrustPolicy, and returns an error if it does not verify. It backs the standalone
+// `porter verify` command, and Porter.Install and Porter.Upgrade also call it before proceeding
+// whenever their own --trust-policy is configured.
+func (p *Porter) Verify(opts VerifyOptions) error {
+	var err error
+	if opts.File != "" {
+		err = p.Config.LoadManifestFrom(opts.File)
+	} else {
+		err = p.Config.LoadManifest()
+	}
+	if err != nil {
+		return err
+	}
+
+	if p.Config.Manifest.BundleTag == "" {
+		return errors.New("porter.yaml must specify a `tag` value for this bundle")
+	}
+	ref, err := parseOCIReference(p.Config.Manifest.BundleTag)
+	if err != nil {
+		return errors.Wrap(err, "invalid bundle tag reference. expected value is REGISTRY/bundle:tag")
+	}
+
+	policy, err := sign.LoadTrustPolicy(opts.TrustPolicy)
+	if err != nil {
+		return err
+	}
+
+	insecureRegistries := []string{}
+	if opts.InsecureRegistry {
+		insecureRegistries = append(insecureRegistries, reference.Domain(ref))
+	}
+	resolverConfig := p.createResolver(insecureRegistries)
+
+	ctx := context.Background()
+	dgst, err := resolveDigest(ctx, resolverConfig, ref.String())
+	if err != nil {
+		return errors.Wrap(err, "unable to resolve bundle digest")
+	}
+
+	fetchSignature := func(ctx context.Context) (*sign.Signature, error) {
+		return fetchBundleSignature(ctx, resolverConfig, ref.String(), dgst)
+	}
+
+	if err := sign.Verify(ctx, fetchSignature, ref.String(), dgst, policy); err != nil {
+		return errors.Wrap(err, "bundle signature verification failed")
+	}
+	fmt.Fprintf(p.Out, "Bundle tag %s, digest %s verified against trust policy %s\n", ref, dgst, opts.TrustPolicy)
+	return nil
+}
+
+func resolveDigest(ctx context.Context, resolverConfig remotes.ResolverConfig, ref string) (digest.Digest, error) {
+	desc, _, err := resolverConfig.Resolver.Resolve(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return desc.Digest, nil
+}
+
+func fetchBundleSignature(ctx context.Context, resolverConfig remotes.ResolverConfig, ref string, dgst digest.Digest) (*sign.Signature, error) {
+	sigTag := fmt.Sprintf("%s:sha256-%s.sig", repositoryOf(ref), dgst.Encoded())
+	sigDesc, fetcher, err := resolverConfig.Resolver.Resolve(ctx, sigTag)
+	if err != nil {
+		return nil, errors.Wrapf(err, "no signature found at %s", sigTag)
+	}
+	rc, err := fetcher.Fetch(ctx, sigDesc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	var sig sign.Signature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return nil, errors.Wrap(err, "unable to parse signature")
+	}
+	return &sig, nil
+}
+
+func repositoryOf(ref string) string {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return ref
+	}
+	return named.Name()
+}